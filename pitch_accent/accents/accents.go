@@ -0,0 +1,60 @@
+// Copyright 2023 David Sansome
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package accents reads and writes the binary pitch accent index shipped
+// inside the app. Write serializes a built index; Open memory-maps one and
+// answers Lookup queries in O(log n) without unmarshaling the whole thing.
+package accents
+
+// PossibleAccents is one part-of-speech's set of possible pitch accents for
+// a reading, along with which sources contributed it and how authoritative
+// it is.
+type PossibleAccents struct {
+	PartsOfSpeech []string
+	Accents       []int
+	// Patterns and Shapes are the pitch pattern classification and per-mora
+	// H/L shape of the corresponding entry in Accents, precomputed from the
+	// reading's moras so the app can draw the pitch graph without
+	// re-implementing mora segmentation.
+	Patterns []string
+	Shapes   []string
+	// Sources lists the name of every -source flag that agreed on these
+	// accents for this part of speech.
+	Sources []string
+	Score   float64
+}
+
+// ReadingAndAccents is one of a vocab's readings, together with every
+// possibleAccents entry recorded for it.
+type ReadingAndAccents struct {
+	Reading    string
+	PrimaryPOS string
+	Accents    []PossibleAccents
+}
+
+const (
+	magicBytes    = "PACC"
+	formatVersion = 1
+
+	// directoryEntrySize is the on-disk width of one vocab directory entry:
+	// uint32 vocabOffset, uint16 vocabLen, uint32 recordOffset, uint32 recordLen.
+	directoryEntrySize = 14
+)
+
+type directoryEntry struct {
+	vocabOffset  uint32
+	vocabLen     uint16
+	recordOffset uint32
+	recordLen    uint32
+}
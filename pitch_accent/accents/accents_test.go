@@ -0,0 +1,161 @@
+// Copyright 2023 David Sansome
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package accents
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWriteAndLookup(t *testing.T) {
+	vocabToReadings := map[string][]ReadingAndAccents{
+		"橋": {
+			{
+				Reading:    "はし",
+				PrimaryPOS: "名",
+				Accents: []PossibleAccents{
+					{PartsOfSpeech: []string{"名"}, Accents: []int{2}, Patterns: []string{"尾高"}, Shapes: []string{"LH"}, Sources: []string{"nhk"}, Score: 2},
+				},
+			},
+		},
+		"端": {
+			{
+				Reading: "はし",
+				Accents: []PossibleAccents{
+					{PartsOfSpeech: []string{"名"}, Accents: []int{0}, Patterns: []string{"平板"}, Shapes: []string{"LH+particle-H"}, Sources: []string{"nhk"}, Score: 1},
+					{PartsOfSpeech: []string{"名"}, Accents: []int{0, 1}, Patterns: []string{"平板", "頭高"}, Shapes: []string{"LH+particle-H", "HL"}, Sources: []string{"shinmeikai"}, Score: 0.5},
+				},
+			},
+		},
+		"日本": {
+			{Reading: "にほん", Accents: []PossibleAccents{
+				{PartsOfSpeech: []string{"名"}, Accents: []int{2}, Patterns: []string{"中高"}, Shapes: []string{"LHL"}, Sources: []string{"nhk"}, Score: 1},
+			}},
+			{Reading: "にっぽん", Accents: []PossibleAccents{
+				{PartsOfSpeech: []string{"名"}, Accents: []int{2}, Patterns: []string{"中高"}, Shapes: []string{"LHLL"}, Sources: []string{"nhk"}, Score: 1},
+			}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, vocabToReadings); err != nil {
+		t.Fatalf("Write() = %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "accents.bin")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() = %v", err)
+	}
+
+	idx, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() = %v", err)
+	}
+	defer idx.Close()
+
+	got := idx.Lookup("橋", "はし")
+	want := []PossibleAccents{
+		{PartsOfSpeech: []string{"名"}, Accents: []int{2}, Patterns: []string{"尾高"}, Shapes: []string{"LH"}, Sources: []string{"nhk"}, Score: 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Lookup(橋, はし) = %+v, want %+v", got, want)
+	}
+
+	got = idx.Lookup("端", "はし")
+	want = []PossibleAccents{
+		{PartsOfSpeech: []string{"名"}, Accents: []int{0}, Patterns: []string{"平板"}, Shapes: []string{"LH+particle-H"}, Sources: []string{"nhk"}, Score: 1},
+		{PartsOfSpeech: []string{"名"}, Accents: []int{0, 1}, Patterns: []string{"平板", "頭高"}, Shapes: []string{"LH+particle-H", "HL"}, Sources: []string{"shinmeikai"}, Score: 0.5},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Lookup(端, はし) = %+v, want %+v", got, want)
+	}
+
+	got = idx.Lookup("日本", "にっぽん")
+	want = []PossibleAccents{
+		{PartsOfSpeech: []string{"名"}, Accents: []int{2}, Patterns: []string{"中高"}, Shapes: []string{"LHLL"}, Sources: []string{"nhk"}, Score: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Lookup(日本, にっぽん) = %+v, want %+v", got, want)
+	}
+
+	if got := idx.Lookup("日本", "無い"); got != nil {
+		t.Errorf("Lookup(日本, 無い) = %+v, want nil", got)
+	}
+	if got := idx.Lookup("存在しない", "はし"); got != nil {
+		t.Errorf("Lookup(存在しない, はし) = %+v, want nil", got)
+	}
+}
+
+// TestCorruptIndex checks that parseIndex and Lookup recover from truncated
+// or otherwise corrupt data instead of panicking.
+func TestCorruptIndex(t *testing.T) {
+	vocabToReadings := map[string][]ReadingAndAccents{
+		"橋": {
+			{
+				Reading: "はし",
+				Accents: []PossibleAccents{
+					{PartsOfSpeech: []string{"名"}, Accents: []int{2}, Patterns: []string{"尾高"}, Shapes: []string{"LH"}, Sources: []string{"nhk"}, Score: 2},
+				},
+			},
+		},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, vocabToReadings); err != nil {
+		t.Fatalf("Write() = %v", err)
+	}
+	full := buf.Bytes()
+
+	t.Run("wrong version", func(t *testing.T) {
+		data := append([]byte(nil), full...)
+		data[len(magicBytes)] = formatVersion + 1
+		if _, err := parseIndex(data); err == nil {
+			t.Error("parseIndex() with unsupported version = nil error, want one")
+		}
+	})
+
+	t.Run("bad magic", func(t *testing.T) {
+		data := append([]byte(nil), full...)
+		data[0] = 'X'
+		if _, err := parseIndex(data); err == nil {
+			t.Error("parseIndex() with bad magic = nil error, want one")
+		}
+	})
+
+	for cut := 1; cut < len(full); cut *= 2 {
+		cut := cut
+		t.Run(fmt.Sprintf("truncated at %d bytes", cut), func(t *testing.T) {
+			// Copy into a freshly allocated slice so it has no spare
+			// capacity: re-slicing full[:cut] directly would still let
+			// reads beyond cut succeed by reaching into full's backing
+			// array.
+			data := make([]byte, cut)
+			copy(data, full)
+			idx, err := parseIndex(data)
+			if err != nil {
+				// Truncation can be caught either while parsing the header
+				// sections or, if the header happens to still parse, while
+				// walking a now-truncated record during Lookup.
+				return
+			}
+			if got := idx.Lookup("橋", "はし"); got != nil {
+				t.Errorf("Lookup() on truncated index = %+v, want nil", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+// Copyright 2023 David Sansome
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package accents
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"syscall"
+)
+
+// Index is a read-only, memory-mapped accents index produced by Write. Use
+// Lookup to find the possible accents for a vocab/reading pair without
+// unmarshaling the whole index.
+type Index struct {
+	data        []byte
+	strs        []string
+	vocabBlob   []byte
+	dir         []byte
+	recordsBase int
+}
+
+// Open memory-maps the binary index at path, ready for random-access
+// Lookup calls.
+func Open(path string) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() == 0 {
+		return nil, fmt.Errorf("accents: %s is empty", path)
+	}
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+
+	idx, err := parseIndex(data)
+	if err != nil {
+		syscall.Munmap(data)
+		return nil, err
+	}
+	return idx, nil
+}
+
+// Close unmaps the index's backing memory.
+func (idx *Index) Close() error {
+	return syscall.Munmap(idx.data)
+}
+
+// parseIndex reads data's sections. data may come from a truncated or
+// corrupted file, so an out-of-range slice while walking it is recovered
+// into an error rather than left to panic.
+func parseIndex(data []byte) (idx *Index, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			idx, err = nil, fmt.Errorf("accents: corrupt index: %v", r)
+		}
+	}()
+
+	if len(data) < len(magicBytes)+1 || string(data[:len(magicBytes)]) != magicBytes {
+		return nil, fmt.Errorf("accents: not a valid index file")
+	}
+	if v := data[len(magicBytes)]; v != formatVersion {
+		return nil, fmt.Errorf("accents: unsupported index version %d", v)
+	}
+	off := len(magicBytes) + 1
+
+	strCount, n := binary.Uvarint(data[off:])
+	off += n
+	strs := make([]string, strCount)
+	for i := range strs {
+		var strLen uint64
+		strLen, n = binary.Uvarint(data[off:])
+		off += n
+		strs[i] = string(data[off : off+int(strLen)])
+		off += int(strLen)
+	}
+
+	vocabBlobLen, n := binary.Uvarint(data[off:])
+	off += n
+	vocabBlob := data[off : off+int(vocabBlobLen)]
+	off += int(vocabBlobLen)
+
+	vocabCount, n := binary.Uvarint(data[off:])
+	off += n
+	dirLen := int(vocabCount) * directoryEntrySize
+	dir := data[off : off+dirLen]
+	off += dirLen
+
+	return &Index{
+		data:        data,
+		strs:        strs,
+		vocabBlob:   vocabBlob,
+		dir:         dir,
+		recordsBase: off,
+	}, nil
+}
+
+func (idx *Index) entryAt(i int) directoryEntry {
+	return decodeDirectoryEntry(idx.dir[i*directoryEntrySize : (i+1)*directoryEntrySize])
+}
+
+func (idx *Index) vocabOf(e directoryEntry) []byte {
+	return idx.vocabBlob[e.vocabOffset : e.vocabOffset+uint32(e.vocabLen)]
+}
+
+// Lookup returns the possible accents recorded for reading under vocab, or
+// nil if the index has no entry for that pair or its record is corrupt.
+func (idx *Index) Lookup(vocab, reading string) (result []PossibleAccents) {
+	defer func() {
+		if recover() != nil {
+			result = nil
+		}
+	}()
+
+	dirCount := len(idx.dir) / directoryEntrySize
+	target := []byte(vocab)
+	i := sort.Search(dirCount, func(i int) bool {
+		return bytes.Compare(idx.vocabOf(idx.entryAt(i)), target) >= 0
+	})
+	if i >= dirCount || !bytes.Equal(idx.vocabOf(idx.entryAt(i)), target) {
+		return nil
+	}
+
+	e := idx.entryAt(i)
+	record := idx.data[idx.recordsBase+int(e.recordOffset) : idx.recordsBase+int(e.recordOffset)+int(e.recordLen)]
+	return idx.decodeRecord(record, reading)
+}
+
+// decodeRecord walks record looking for the reading entry matching reading,
+// decoding only as much of it as it needs to.
+func (idx *Index) decodeRecord(record []byte, reading string) []PossibleAccents {
+	off := 0
+	readingCount, n := binary.Uvarint(record[off:])
+	off += n
+	for i := uint64(0); i < readingCount; i++ {
+		var readingID uint64
+		readingID, n = binary.Uvarint(record[off:])
+		off += n
+
+		hasPrimaryPOS := record[off]
+		off++
+		if hasPrimaryPOS == 1 {
+			_, n = binary.Uvarint(record[off:])
+			off += n
+		}
+
+		accentGroupCount, n := binary.Uvarint(record[off:])
+		off += n
+
+		if idx.strs[readingID] != reading {
+			off = skipAccentGroups(record, off, accentGroupCount)
+			continue
+		}
+
+		result := make([]PossibleAccents, accentGroupCount)
+		for g := range result {
+			result[g], off = idx.decodeAccentGroup(record, off)
+		}
+		return result
+	}
+	return nil
+}
+
+func (idx *Index) decodeAccentGroup(record []byte, off int) (PossibleAccents, int) {
+	posCount, n := binary.Uvarint(record[off:])
+	off += n
+	pos := make([]string, posCount)
+	for i := range pos {
+		var id uint64
+		id, n = binary.Uvarint(record[off:])
+		off += n
+		pos[i] = idx.strs[id]
+	}
+
+	accentCount, n := binary.Uvarint(record[off:])
+	off += n
+	accentInts := make([]int, accentCount)
+	for i := range accentInts {
+		var v uint64
+		v, n = binary.Uvarint(record[off:])
+		off += n
+		accentInts[i] = int(v)
+	}
+
+	// Patterns and Shapes are parallel to Accents, so they share its count.
+	patterns := make([]string, accentCount)
+	for i := range patterns {
+		var id uint64
+		id, n = binary.Uvarint(record[off:])
+		off += n
+		patterns[i] = idx.strs[id]
+	}
+	shapes := make([]string, accentCount)
+	for i := range shapes {
+		var id uint64
+		id, n = binary.Uvarint(record[off:])
+		off += n
+		shapes[i] = idx.strs[id]
+	}
+
+	score := math.Float64frombits(binary.BigEndian.Uint64(record[off : off+8]))
+	off += 8
+
+	sourceCount, n := binary.Uvarint(record[off:])
+	off += n
+	sources := make([]string, sourceCount)
+	for i := range sources {
+		var id uint64
+		id, n = binary.Uvarint(record[off:])
+		off += n
+		sources[i] = idx.strs[id]
+	}
+
+	return PossibleAccents{
+		PartsOfSpeech: pos,
+		Accents:       accentInts,
+		Patterns:      patterns,
+		Shapes:        shapes,
+		Sources:       sources,
+		Score:         score,
+	}, off
+}
+
+// skipAccentGroups advances past count accent groups without decoding them,
+// returning the offset just after the last one.
+func skipAccentGroups(record []byte, off int, count uint64) int {
+	for i := uint64(0); i < count; i++ {
+		posCount, n := binary.Uvarint(record[off:])
+		off += n
+		for j := uint64(0); j < posCount; j++ {
+			_, n = binary.Uvarint(record[off:])
+			off += n
+		}
+
+		accentCount, n := binary.Uvarint(record[off:])
+		off += n
+		for j := uint64(0); j < accentCount; j++ {
+			_, n = binary.Uvarint(record[off:])
+			off += n
+		}
+		// Patterns and Shapes are parallel to Accents, so they share its count.
+		for j := uint64(0); j < accentCount*2; j++ {
+			_, n = binary.Uvarint(record[off:])
+			off += n
+		}
+
+		off += 8 // score
+
+		sourceCount, n := binary.Uvarint(record[off:])
+		off += n
+		for j := uint64(0); j < sourceCount; j++ {
+			_, n = binary.Uvarint(record[off:])
+			off += n
+		}
+	}
+	return off
+}
@@ -0,0 +1,228 @@
+// Copyright 2023 David Sansome
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package accents
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+	"sort"
+)
+
+// stringTable interns strings (POS tags and readings) so repeats across the
+// index only cost a varint reference instead of being written out in full
+// every time.
+type stringTable struct {
+	ids   map[string]int
+	order []string
+}
+
+func newStringTable() *stringTable {
+	return &stringTable{ids: make(map[string]int)}
+}
+
+func (t *stringTable) id(s string) int {
+	if id, ok := t.ids[s]; ok {
+		return id
+	}
+	id := len(t.order)
+	t.ids[s] = id
+	t.order = append(t.order, s)
+	return id
+}
+
+// Write serializes vocabToReadings to w as the binary index format: a
+// string table of interned POS tags and readings, a sorted fixed-width
+// vocab directory for O(log n) lookup, and the per-vocab records the
+// directory points into. Because the directory and string table have to be
+// complete before the first byte of either can be written, Write builds
+// them in memory rather than streaming; it's the read side (Open/Lookup,
+// via mmap) that avoids unmarshaling the whole index.
+func Write(w io.Writer, vocabToReadings map[string][]ReadingAndAccents) error {
+	vocabs := make([]string, 0, len(vocabToReadings))
+	for vocab := range vocabToReadings {
+		vocabs = append(vocabs, vocab)
+	}
+	sort.Strings(vocabs)
+
+	strs := newStringTable()
+	for _, vocab := range vocabs {
+		for _, ra := range vocabToReadings[vocab] {
+			strs.id(ra.Reading)
+			if ra.PrimaryPOS != "" {
+				strs.id(ra.PrimaryPOS)
+			}
+			for _, pa := range ra.Accents {
+				for _, pos := range pa.PartsOfSpeech {
+					strs.id(pos)
+				}
+				for _, p := range pa.Patterns {
+					strs.id(p)
+				}
+				for _, s := range pa.Shapes {
+					strs.id(s)
+				}
+				for _, s := range pa.Sources {
+					strs.id(s)
+				}
+			}
+		}
+	}
+
+	var vocabBlob, dir, recordBlob []byte
+	for _, vocab := range vocabs {
+		record := encodeRecord(strs, vocabToReadings[vocab])
+		dir = appendDirectoryEntry(dir, directoryEntry{
+			vocabOffset:  uint32(len(vocabBlob)),
+			vocabLen:     uint16(len(vocab)),
+			recordOffset: uint32(len(recordBlob)),
+			recordLen:    uint32(len(record)),
+		})
+		vocabBlob = append(vocabBlob, vocab...)
+		recordBlob = append(recordBlob, record...)
+	}
+
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(magicBytes); err != nil {
+		return err
+	}
+	if err := bw.WriteByte(formatVersion); err != nil {
+		return err
+	}
+	if err := writeStringTable(bw, strs); err != nil {
+		return err
+	}
+	if err := writeUvarintBlob(bw, vocabBlob); err != nil {
+		return err
+	}
+	if err := writeUvarint(bw, uint64(len(vocabs))); err != nil {
+		return err
+	}
+	if _, err := bw.Write(dir); err != nil {
+		return err
+	}
+	if _, err := bw.Write(recordBlob); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func writeStringTable(w *bufio.Writer, strs *stringTable) error {
+	if err := writeUvarint(w, uint64(len(strs.order))); err != nil {
+		return err
+	}
+	for _, s := range strs.order {
+		if err := writeUvarintBlob(w, []byte(s)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeUvarint(w *bufio.Writer, v uint64) error {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	_, err := w.Write(buf[:n])
+	return err
+}
+
+// writeUvarintBlob writes b's length as a uvarint, then b itself.
+func writeUvarintBlob(w *bufio.Writer, b []byte) error {
+	if err := writeUvarint(w, uint64(len(b))); err != nil {
+		return err
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	return append(buf, tmp[:n]...)
+}
+
+func appendUvarintBlob(buf []byte, b []byte) []byte {
+	buf = appendUvarint(buf, uint64(len(b)))
+	return append(buf, b...)
+}
+
+func appendFloat64(buf []byte, f float64) []byte {
+	var tmp [8]byte
+	binary.BigEndian.PutUint64(tmp[:], math.Float64bits(f))
+	return append(buf, tmp[:]...)
+}
+
+func appendDirectoryEntry(buf []byte, e directoryEntry) []byte {
+	var tmp [directoryEntrySize]byte
+	binary.BigEndian.PutUint32(tmp[0:4], e.vocabOffset)
+	binary.BigEndian.PutUint16(tmp[4:6], e.vocabLen)
+	binary.BigEndian.PutUint32(tmp[6:10], e.recordOffset)
+	binary.BigEndian.PutUint32(tmp[10:14], e.recordLen)
+	return append(buf, tmp[:]...)
+}
+
+func decodeDirectoryEntry(b []byte) directoryEntry {
+	return directoryEntry{
+		vocabOffset:  binary.BigEndian.Uint32(b[0:4]),
+		vocabLen:     binary.BigEndian.Uint16(b[4:6]),
+		recordOffset: binary.BigEndian.Uint32(b[6:10]),
+		recordLen:    binary.BigEndian.Uint32(b[10:14]),
+	}
+}
+
+// encodeRecord serializes one vocab's readings and their accents.
+func encodeRecord(strs *stringTable, readings []ReadingAndAccents) []byte {
+	var buf []byte
+	buf = appendUvarint(buf, uint64(len(readings)))
+	for _, ra := range readings {
+		buf = appendUvarint(buf, uint64(strs.id(ra.Reading)))
+		if ra.PrimaryPOS == "" {
+			buf = append(buf, 0)
+		} else {
+			buf = append(buf, 1)
+			buf = appendUvarint(buf, uint64(strs.id(ra.PrimaryPOS)))
+		}
+
+		buf = appendUvarint(buf, uint64(len(ra.Accents)))
+		for _, pa := range ra.Accents {
+			buf = appendUvarint(buf, uint64(len(pa.PartsOfSpeech)))
+			for _, pos := range pa.PartsOfSpeech {
+				buf = appendUvarint(buf, uint64(strs.id(pos)))
+			}
+
+			buf = appendUvarint(buf, uint64(len(pa.Accents)))
+			for _, accent := range pa.Accents {
+				buf = appendUvarint(buf, uint64(accent))
+			}
+			// Patterns and Shapes are parallel to Accents, so their lengths
+			// aren't written separately.
+			for _, p := range pa.Patterns {
+				buf = appendUvarint(buf, uint64(strs.id(p)))
+			}
+			for _, s := range pa.Shapes {
+				buf = appendUvarint(buf, uint64(strs.id(s)))
+			}
+
+			buf = appendFloat64(buf, pa.Score)
+
+			buf = appendUvarint(buf, uint64(len(pa.Sources)))
+			for _, s := range pa.Sources {
+				buf = appendUvarint(buf, uint64(strs.id(s)))
+			}
+		}
+	}
+	return buf
+}
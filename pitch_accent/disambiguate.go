@@ -0,0 +1,71 @@
+// Copyright 2023 David Sansome
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"github.com/ikawaha/kagome-dict/ipa"
+	"github.com/ikawaha/kagome/v2/tokenizer"
+)
+
+// posCodeByKagomeTag maps the part-of-speech tags kagome's IPA dictionary
+// assigns (the first feature of a token) to the short Japanese POS codes
+// used as keys in accents.txt, e.g. "(名)2,(副)1,2".
+var posCodeByKagomeTag = map[string]string{
+	"名詞":   "名",
+	"代名詞":  "代",
+	"副詞":   "副",
+	"動詞":   "動",
+	"形容詞":  "形",
+	"形容動詞": "形動",
+	"連体詞":  "連体",
+	"接続詞":  "接続",
+	"感動詞":  "感",
+	"助詞":   "助",
+	"助動詞":  "助動",
+}
+
+// disambiguator uses a kagome morphological analyzer to guess the part of
+// speech a vocab's surface form is most likely to take, so that the correct
+// entry among a word's possibleAccents can be pre-selected.
+type disambiguator struct {
+	tok *tokenizer.Tokenizer
+}
+
+func newDisambiguator() (*disambiguator, error) {
+	tok, err := tokenizer.New(ipa.Dict(), tokenizer.OmitBosEos())
+	if err != nil {
+		return nil, err
+	}
+	return &disambiguator{tok: tok}, nil
+}
+
+// primaryPOS returns the short POS code for the most likely reading of
+// vocab, or "" if kagome didn't recognize it or its tag doesn't map to one
+// of the codes used in accents.txt. The guess is keyed on vocab alone, not
+// on a specific reading, so a heteronym with multiple readings listed in
+// accents.txt gets the same primaryPOS for each of them.
+func (d *disambiguator) primaryPOS(vocab string) string {
+	tokens := d.tok.Tokenize(vocab)
+	for _, tok := range tokens {
+		features := tok.Features()
+		if len(features) == 0 {
+			continue
+		}
+		if code, ok := posCodeByKagomeTag[features[0]]; ok {
+			return code
+		}
+	}
+	return ""
+}
@@ -0,0 +1,46 @@
+// Copyright 2023 David Sansome
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import "testing"
+
+func TestPosCodeByKagomeTag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{"名詞", "名"},
+		{"代名詞", "代"},
+		{"副詞", "副"},
+		{"動詞", "動"},
+		{"形容詞", "形"},
+		{"形容動詞", "形動"},
+		{"連体詞", "連体"},
+		{"接続詞", "接続"},
+		{"感動詞", "感"},
+		{"助詞", "助"},
+		{"助動詞", "助動"},
+	}
+	if got, want := len(posCodeByKagomeTag), len(tests); got != want {
+		t.Errorf("len(posCodeByKagomeTag) = %d, want %d", got, want)
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			if got, ok := posCodeByKagomeTag[tt.tag]; !ok || got != tt.want {
+				t.Errorf("posCodeByKagomeTag[%q] = %q, %v, want %q, true", tt.tag, got, ok, tt.want)
+			}
+		})
+	}
+}
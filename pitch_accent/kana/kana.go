@@ -0,0 +1,99 @@
+// Copyright 2023 David Sansome
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package kana normalizes Japanese kana readings to hiragana.
+package kana
+
+import "strings"
+
+const (
+	katakanaStart = 0x30A1
+	katakanaEnd   = 0x30F6
+	hiraganaStart = 0x3041
+
+	halfwidthKatakanaStart = 0xFF66
+	halfwidthKatakanaEnd   = 0xFF9D
+
+	prolongedSoundMark = 0x30FC
+)
+
+// halfwidthFullwidthKatakana lists the fullwidth katakana equivalent of each
+// halfwidth katakana rune from U+FF66 to U+FF9D, in code point order.
+const halfwidthFullwidthKatakana = "ヲァィゥェォャュョッーアイウエオカキクケコサシスセソタチツテトナニヌネノハヒフヘホマミムメモヤユヨラリルレロワン"
+
+// halfwidthToFullwidthKatakana maps halfwidth katakana runes (U+FF66-U+FF9D)
+// to their fullwidth katakana equivalents, so they can be folded to hiragana
+// by the same table used for fullwidth katakana.
+var halfwidthToFullwidthKatakana = buildHalfwidthToFullwidthKatakana()
+
+func buildHalfwidthToFullwidthKatakana() map[rune]rune {
+	fullwidth := []rune(halfwidthFullwidthKatakana)
+	m := make(map[rune]rune, len(fullwidth))
+	for i, r := range fullwidth {
+		m[halfwidthKatakanaStart+rune(i)] = r
+	}
+	return m
+}
+
+// vowelForHiragana maps a hiragana mora to the hiragana rune a following
+// prolonged-sound mark (ー) expands to, so that e.g. ロー -> ろう,
+// ケー -> けえ. Following standard hiragana orthography, o-row moras expand
+// with う rather than お.
+var vowelForHiragana = map[rune]rune{
+	'あ': 'あ', 'か': 'あ', 'さ': 'あ', 'た': 'あ', 'な': 'あ', 'は': 'あ', 'ま': 'あ', 'や': 'あ', 'ら': 'あ', 'わ': 'あ',
+	'が': 'あ', 'ざ': 'あ', 'だ': 'あ', 'ば': 'あ', 'ぱ': 'あ', 'ゃ': 'あ', 'ぁ': 'あ', 'ゎ': 'あ',
+
+	'い': 'い', 'き': 'い', 'し': 'い', 'ち': 'い', 'に': 'い', 'ひ': 'い', 'み': 'い', 'り': 'い',
+	'ぎ': 'い', 'じ': 'い', 'ぢ': 'い', 'び': 'い', 'ぴ': 'い', 'ぃ': 'い',
+
+	'う': 'う', 'く': 'う', 'す': 'う', 'つ': 'う', 'ぬ': 'う', 'ふ': 'う', 'む': 'う', 'ゆ': 'う', 'る': 'う',
+	'ぐ': 'う', 'ず': 'う', 'づ': 'う', 'ぶ': 'う', 'ぷ': 'う', 'ゅ': 'う', 'ぅ': 'う', 'っ': 'う',
+
+	'え': 'え', 'け': 'え', 'せ': 'え', 'て': 'え', 'ね': 'え', 'へ': 'え', 'め': 'え', 'れ': 'え',
+	'げ': 'え', 'ぜ': 'え', 'で': 'え', 'べ': 'え', 'ぺ': 'え', 'ぇ': 'え',
+
+	'お': 'う', 'こ': 'う', 'そ': 'う', 'と': 'う', 'の': 'う', 'ほ': 'う', 'も': 'う', 'よ': 'う', 'ろ': 'う', 'を': 'う',
+	'ご': 'う', 'ぞ': 'う', 'ど': 'う', 'ぼ': 'う', 'ぽ': 'う', 'ょ': 'う', 'ぉ': 'う',
+}
+
+// ToHiragana normalizes s by converting fullwidth and halfwidth katakana to
+// hiragana. The prolonged-sound mark (ー) is expanded to the vowel of the
+// preceding mora when that mora ends up as hiragana, e.g. ロー -> ろう,
+// ケー -> けえ. Runes that aren't kana, such as kanji and punctuation, are
+// left untouched.
+func ToHiragana(s string) string {
+	var b strings.Builder
+	var prevVowel rune
+	for _, r := range s {
+		if full, ok := halfwidthToFullwidthKatakana[r]; ok {
+			r = full
+		}
+
+		switch {
+		case r == prolongedSoundMark && prevVowel != 0:
+			// The expanded vowel is itself a valid base for a further ー.
+			b.WriteRune(prevVowel)
+
+		case r >= katakanaStart && r <= katakanaEnd:
+			h := r - katakanaStart + hiraganaStart
+			b.WriteRune(h)
+			prevVowel = vowelForHiragana[h]
+
+		default:
+			b.WriteRune(r)
+			prevVowel = vowelForHiragana[r]
+		}
+	}
+	return b.String()
+}
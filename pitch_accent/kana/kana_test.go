@@ -0,0 +1,47 @@
+// Copyright 2023 David Sansome
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kana
+
+import "testing"
+
+func TestToHiragana(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain hiragana is unchanged", "はし", "はし"},
+		{"plain katakana", "カタカナ", "かたかな"},
+		{"long vowel after o-row", "ロー", "ろう"},
+		{"long vowel after e-row", "ケー", "けえ"},
+		{"long vowel at start is left alone", "ーキ", "ーき"},
+		{"small kana", "キャベツ", "きゃべつ"},
+		{"small kana before long vowel", "ニュース", "にゅうす"},
+		{"small tsu", "ッキ", "っき"},
+		{"small yo", "ギョ", "ぎょ"},
+		{"long vowel after small tsu", "ッー", "っう"},
+		{"halfwidth katakana", "ｶﾀｶﾅ", "かたかな"},
+		{"halfwidth long vowel", "ｺｰﾋｰ", "こうひい"},
+		{"mixed script with kanji", "東京タワー", "東京たわあ"},
+		{"mixed script with punctuation", "ＯＫ、カンジ！", "ＯＫ、かんじ！"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToHiragana(tt.in); got != tt.want {
+				t.Errorf("ToHiragana(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -18,13 +18,33 @@ import (
 	"bufio"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+
+	"github.com/buonuomo/tsurukame/pitch_accent/accents"
+	"github.com/buonuomo/tsurukame/pitch_accent/kana"
 )
 
+var disambiguateFlag = flag.Bool("disambiguate", true, "run each vocab through a kagome morphological analyzer to "+
+	"pick out its likely part of speech, so the app can pre-select the right accent. Requires the kagome IPA "+
+	"dictionary to be available at build time")
+
+var formatFlag = flag.String("format", "binary", "output format: \"binary\" (the default, for shipping inside the "+
+	"app) or \"json\" (for debugging)")
+
+var sourceFlags sourceList
+
+func init() {
+	flag.Var(&sourceFlags, "source", "an accents file to merge into the index, in the form name=path[:weight]. "+
+		"May be repeated to layer multiple accent sources, e.g. NHK, Shin-Meikai, or community-contributed lists; "+
+		"weight defaults to 1 and scales how much that source's accents contribute to a possibleAccents' score")
+}
+
 const accentsFile = "accents.txt"
 
 var lineRegex = regexp.MustCompile(`^([^\t]+)\t([^\t]*)\t([^\t]+)$`)
@@ -35,27 +55,125 @@ var semicolonRegex = regexp.MustCompile(`;`)
 type possibleAccents struct {
 	partsOfSpeech []string
 	accents       []int
+	// sources lists the name of every -source flag that agreed on these
+	// accents for this part of speech.
+	sources []string
+	// score is how authoritative this entry is, the sum of the weights of
+	// every source that agreed on these accents for this part of speech.
+	score float64
 }
 
-func (p possibleAccents) String() string {
+// format renders p as "partsOfSpeech;accent(pattern:shape),...;score",
+// computing each accent's pitch pattern and shape from reading's moras.
+func (p possibleAccents) format(reading string) string {
+	n := len(moras(reading))
 	accentStrs := make([]string, len(p.accents))
 	for i, accent := range p.accents {
-		accentStrs[i] = strconv.Itoa(accent)
+		accentStrs[i] = fmt.Sprintf("%d(%s:%s)", accent, pitchPattern(n, accent), pitchShape(n, accent))
+	}
+	return fmt.Sprintf("%v;%v;%v", strings.Join(p.partsOfSpeech, ","), strings.Join(accentStrs, ","),
+		strconv.FormatFloat(p.score, 'g', -1, 64))
+}
+
+// samePartsOfSpeech reports whether a and b name the same set of parts of
+// speech, regardless of order.
+func samePartsOfSpeech(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	aSorted := append([]string(nil), a...)
+	bSorted := append([]string(nil), b...)
+	sort.Strings(aSorted)
+	sort.Strings(bSorted)
+	for i := range aSorted {
+		if aSorted[i] != bSorted[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeAccents merges incoming into existing, in place: entries that share
+// a part-of-speech set have their accent integers deduped, their sources
+// combined, and their scores summed, and any genuinely new entries are
+// appended.
+func mergeAccents(existing []possibleAccents, incoming []possibleAccents) []possibleAccents {
+	for _, in := range incoming {
+		merged := false
+		for i, ex := range existing {
+			if !samePartsOfSpeech(ex.partsOfSpeech, in.partsOfSpeech) {
+				continue
+			}
+			existing[i].accents = dedupeInts(append(existing[i].accents, in.accents...))
+			existing[i].sources = dedupeStrings(append(existing[i].sources, in.sources...))
+			existing[i].score += in.score
+			merged = true
+			break
+		}
+		if !merged {
+			existing = append(existing, in)
+		}
 	}
-	return fmt.Sprintf("%v;%v", strings.Join(p.partsOfSpeech, ","), strings.Join(accentStrs, ","))
+	return existing
+}
+
+// dedupeInts returns the distinct values of ints, in ascending order.
+func dedupeInts(ints []int) []int {
+	seen := make(map[int]bool, len(ints))
+	var res []int
+	for _, i := range ints {
+		if !seen[i] {
+			seen[i] = true
+			res = append(res, i)
+		}
+	}
+	sort.Ints(res)
+	return res
+}
+
+// dedupeStrings returns the distinct values of strs, in first-seen order.
+func dedupeStrings(strs []string) []string {
+	seen := make(map[string]bool, len(strs))
+	var res []string
+	for _, s := range strs {
+		if !seen[s] {
+			seen[s] = true
+			res = append(res, s)
+		}
+	}
+	return res
 }
 
 type readingAndAccents struct {
 	reading string
 	accents []possibleAccents
+	// primaryPOS is the short POS code (see posCodeByKagomeTag) of the
+	// reading's most likely part of speech, as guessed by the disambiguator.
+	// It's empty if disambiguation was disabled or inconclusive.
+	primaryPOS string
+}
+
+// totalScore is the sum of the scores of every possibleAccents entry for
+// this reading, used to rank a vocab's readings by how authoritative they
+// are across all merged sources.
+func (r readingAndAccents) totalScore() float64 {
+	var total float64
+	for _, accent := range r.accents {
+		total += accent.score
+	}
+	return total
 }
 
 func (r readingAndAccents) String() string {
 	accentStrs := make([]string, len(r.accents))
 	for i, accent := range r.accents {
-		accentStrs[i] = accent.String()
+		accentStrs[i] = accent.format(r.reading)
+	}
+	s := fmt.Sprintf("%s:%v", r.reading, strings.Join(accentStrs, "|"))
+	if r.primaryPOS != "" {
+		s = fmt.Sprintf("%s:%s", s, r.primaryPOS)
 	}
-	return fmt.Sprintf("%s:%v", r.reading, strings.Join(accentStrs, "|"))
+	return s
 }
 
 type index struct {
@@ -71,42 +189,95 @@ func newIndex() *index {
 func (idx *index) MarshalJSON() ([]byte, error) {
 	toMarshal := make(map[string][]string)
 	for vocab, readingsAndAccents := range idx.vocabToReadingsAndAccents {
-		toMarshal[vocab] = make([]string, len(readingsAndAccents))
-		for i, readingAndAccents := range readingsAndAccents {
+		// Sort readings by descending score so the app can pick the most
+		// authoritative reading first.
+		sorted := append([]readingAndAccents(nil), readingsAndAccents...)
+		sort.Slice(sorted, func(i, j int) bool {
+			return sorted[i].totalScore() > sorted[j].totalScore()
+		})
+		toMarshal[vocab] = make([]string, len(sorted))
+		for i, readingAndAccents := range sorted {
 			toMarshal[vocab][i] = readingAndAccents.String()
 		}
 	}
 	return json.Marshal(toMarshal)
 }
 
-func (idx *index) add(vocab string, reading string, accents []possibleAccents) {
+// toBinaryFormat converts idx to the shape accents.Write expects.
+func (idx *index) toBinaryFormat() map[string][]accents.ReadingAndAccents {
+	out := make(map[string][]accents.ReadingAndAccents, len(idx.vocabToReadingsAndAccents))
+	for vocab, readingsAndAccents := range idx.vocabToReadingsAndAccents {
+		converted := make([]accents.ReadingAndAccents, len(readingsAndAccents))
+		for i, ra := range readingsAndAccents {
+			n := len(moras(ra.reading))
+			possible := make([]accents.PossibleAccents, len(ra.accents))
+			for j, pa := range ra.accents {
+				patterns := make([]string, len(pa.accents))
+				shapes := make([]string, len(pa.accents))
+				for k, accent := range pa.accents {
+					patterns[k] = pitchPattern(n, accent)
+					shapes[k] = pitchShape(n, accent)
+				}
+				possible[j] = accents.PossibleAccents{
+					PartsOfSpeech: pa.partsOfSpeech,
+					Accents:       pa.accents,
+					Patterns:      patterns,
+					Shapes:        shapes,
+					Sources:       pa.sources,
+					Score:         pa.score,
+				}
+			}
+			converted[i] = accents.ReadingAndAccents{
+				Reading:    ra.reading,
+				PrimaryPOS: ra.primaryPOS,
+				Accents:    possible,
+			}
+		}
+		out[vocab] = converted
+	}
+	return out
+}
+
+func (idx *index) add(vocab string, reading string, accents []possibleAccents, primaryPOS string) {
+	for i, ra := range idx.vocabToReadingsAndAccents[vocab] {
+		if ra.reading != reading {
+			continue
+		}
+		idx.vocabToReadingsAndAccents[vocab][i].accents = mergeAccents(ra.accents, accents)
+		if idx.vocabToReadingsAndAccents[vocab][i].primaryPOS == "" {
+			idx.vocabToReadingsAndAccents[vocab][i].primaryPOS = primaryPOS
+		}
+		return
+	}
 	idx.vocabToReadingsAndAccents[vocab] = append(idx.vocabToReadingsAndAccents[vocab], readingAndAccents{
-		reading: reading,
-		accents: accents,
+		reading:    reading,
+		accents:    accents,
+		primaryPOS: primaryPOS,
 	})
 }
 
-func parseLine(line []byte) (string, string, []possibleAccents, error) {
+func parseLine(line []byte, src source) (string, string, []possibleAccents, error) {
 	matches := lineRegex.FindSubmatch(line)
 	if matches == nil {
 		return "", "", nil, errors.New(fmt.Sprintf("Failed to parse line: %s", line))
 	}
 	vocab := string(matches[1])
-	// TODO: sometimes all or part of the reading will be in katakana. we may need to convert it to hiragana for it to
-	// match what we're getting from wanikani
 	reading := string(matches[2])
 	if reading == "" {
 		// for kana-only words, there is no separate reading listed
 		reading = vocab
 	}
-	possibleAccents, err := parseAccentBytes(matches[3])
+	// WaniKani always sends readings in hiragana, but accents.txt sometimes
+	// has all or part of the reading in katakana.
+	reading = kana.ToHiragana(reading)
+	possibleAccents, err := parseAccentBytes(matches[3], src)
 	if err != nil {
 		return "", "", nil, err
 	}
 	return vocab, reading, possibleAccents, nil
 }
 
-func parseAccentBytes(accents []byte) ([]possibleAccents, error) {
+func parseAccentBytes(accents []byte, src source) ([]possibleAccents, error) {
 	// accents is a comma-separated list of integers optionally prefixed by parts of speech in parens
 	// e.g. "0,2",
 	// e.g. "(名)2,(代)0,2"
@@ -134,34 +305,75 @@ func parseAccentBytes(accents []byte) ([]possibleAccents, error) {
 		res = append(res, possibleAccents{
 			partsOfSpeech: semicolonRegex.Split(partOfSpeech, -1),
 			accents:       accents,
+			sources:       []string{src.name},
+			score:         src.weight,
 		})
 	}
 	return res, nil
 }
 
 func main() {
-	// open accentsFile to read line by line
-	f, err := os.Open(accentsFile)
-	if err != nil {
-		panic(err)
+	flag.Parse()
+
+	if len(sourceFlags) == 0 {
+		sourceFlags = sourceList{{name: "default", path: accentsFile, weight: defaultSourceWeight}}
+	}
+
+	var disambig *disambiguator
+	if *disambiguateFlag {
+		var err error
+		disambig, err = newDisambiguator()
+		if err != nil {
+			panic(err)
+		}
 	}
-	defer f.Close()
 
 	idx := newIndex()
 
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		line := scanner.Bytes()
-		vocab, reading, accents, err := parseLine(line)
+	for _, src := range sourceFlags {
+		if err := addSourceToIndex(idx, src, disambig); err != nil {
+			panic(err)
+		}
+	}
+
+	switch *formatFlag {
+	case "json":
+		res, err := json.Marshal(idx)
 		if err != nil {
 			panic(err)
 		}
-		idx.add(vocab, reading, accents)
+		fmt.Println(string(res))
+	case "binary":
+		if err := accents.Write(os.Stdout, idx.toBinaryFormat()); err != nil {
+			panic(err)
+		}
+	default:
+		panic(fmt.Sprintf("unknown -format %q, want \"binary\" or \"json\"", *formatFlag))
 	}
+}
 
-	res, err := json.Marshal(idx)
+// addSourceToIndex reads src's accents file line by line and merges it into
+// idx. Each line is scanned in isolation, but merging across sources (see
+// mergeAccents) means idx itself still holds every vocab in memory for the
+// whole run rather than being streamed straight through to the output.
+func addSourceToIndex(idx *index, src source, disambig *disambiguator) error {
+	f, err := os.Open(src.path)
 	if err != nil {
-		panic(err)
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		vocab, reading, accents, err := parseLine(scanner.Bytes(), src)
+		if err != nil {
+			return err
+		}
+		primaryPOS := ""
+		if disambig != nil {
+			primaryPOS = disambig.primaryPOS(vocab)
+		}
+		idx.add(vocab, reading, accents, primaryPOS)
 	}
-	fmt.Println(string(res))
+	return scanner.Err()
 }
@@ -0,0 +1,109 @@
+// Copyright 2023 David Sansome
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSamePartsOfSpeech(t *testing.T) {
+	tests := []struct {
+		name string
+		a    []string
+		b    []string
+		want bool
+	}{
+		{"same order", []string{"名", "代"}, []string{"名", "代"}, true},
+		{"different order", []string{"名", "代"}, []string{"代", "名"}, true},
+		{"different length", []string{"名"}, []string{"名", "代"}, false},
+		{"different members", []string{"名"}, []string{"代"}, false},
+		{"both empty", nil, []string{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := samePartsOfSpeech(tt.a, tt.b); got != tt.want {
+				t.Errorf("samePartsOfSpeech(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDedupeInts(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []int
+		want []int
+	}{
+		{"already sorted and unique", []int{0, 1, 2}, []int{0, 1, 2}},
+		{"unsorted with duplicates", []int{2, 0, 2, 1, 0}, []int{0, 1, 2}},
+		{"empty", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dedupeInts(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("dedupeInts(%v) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeAccents(t *testing.T) {
+	existing := []possibleAccents{
+		{partsOfSpeech: []string{"名"}, accents: []int{0}, sources: []string{"nhk"}, score: 1},
+	}
+	incoming := []possibleAccents{
+		// Same part-of-speech set as the existing entry: accents dedupe,
+		// sources combine, and scores sum.
+		{partsOfSpeech: []string{"名"}, accents: []int{0, 1}, sources: []string{"shinmeikai"}, score: 0.5},
+		// A new part-of-speech set: appended as its own entry.
+		{partsOfSpeech: []string{"代"}, accents: []int{2}, sources: []string{"shinmeikai"}, score: 0.5},
+	}
+
+	got := mergeAccents(existing, incoming)
+	want := []possibleAccents{
+		{partsOfSpeech: []string{"名"}, accents: []int{0, 1}, sources: []string{"nhk", "shinmeikai"}, score: 1.5},
+		{partsOfSpeech: []string{"代"}, accents: []int{2}, sources: []string{"shinmeikai"}, score: 0.5},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeAccents() = %+v, want %+v", got, want)
+	}
+}
+
+func TestAddSourceToIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "accents.txt")
+	contents := "橋\tはし\t(名)2\n端\tはし\t(名)0\n"
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() = %v", err)
+	}
+
+	idx := newIndex()
+	src := source{name: "test", path: path, weight: 1}
+	if err := addSourceToIndex(idx, src, nil); err != nil {
+		t.Fatalf("addSourceToIndex() = %v", err)
+	}
+
+	got := idx.vocabToReadingsAndAccents["橋"]
+	want := []readingAndAccents{
+		{reading: "はし", accents: []possibleAccents{
+			{partsOfSpeech: []string{"名"}, accents: []int{2}, sources: []string{"test"}, score: 1},
+		}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("vocabToReadingsAndAccents[橋] = %+v, want %+v", got, want)
+	}
+}
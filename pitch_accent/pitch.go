@@ -0,0 +1,91 @@
+// Copyright 2023 David Sansome
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+// The four canonical Japanese pitch pattern classifications, keyed by where
+// a word's downstep falls relative to its moras.
+const (
+	patternHeiban    = "平板"
+	patternAtamadaka = "頭高"
+	patternNakadaka  = "中高"
+	patternOdaka     = "尾高"
+)
+
+// smallKana are the kana that attach to the preceding mora instead of
+// forming a mora of their own, e.g. きゃ is one mora, not two.
+var smallKana = map[rune]bool{
+	'ゃ': true, 'ゅ': true, 'ょ': true,
+	'ぁ': true, 'ぃ': true, 'ぅ': true, 'ぇ': true, 'ぉ': true,
+}
+
+// moras splits a hiragana reading into its moras. ー, ん, and っ each count
+// as a mora of their own; small kana do not.
+func moras(reading string) []string {
+	var result []string
+	for _, r := range reading {
+		if smallKana[r] && len(result) > 0 {
+			result[len(result)-1] += string(r)
+			continue
+		}
+		result = append(result, string(r))
+	}
+	return result
+}
+
+// pitchPattern classifies a word of n moras whose accent's downstep falls at
+// mora position accent (0 meaning no downstep) into one of the four
+// canonical pitch pattern types.
+func pitchPattern(n, accent int) string {
+	switch {
+	case accent == 0:
+		return patternHeiban
+	case accent == 1:
+		return patternAtamadaka
+	case accent == n:
+		return patternOdaka
+	default:
+		return patternNakadaka
+	}
+}
+
+// pitchShape renders a word of n moras whose accent's downstep falls at mora
+// position accent as a compact per-mora H/L string. A heiban word (accent
+// 0) stays high after the word ends, so "+particle-H" is appended to mark
+// that a following particle keeps the high pitch; every other pattern has
+// already dropped by then, so no suffix is needed.
+func pitchShape(n, accent int) string {
+	hl := make([]byte, n)
+	for i := 1; i <= n; i++ {
+		var high bool
+		switch {
+		case accent == 0:
+			high = i != 1
+		case accent == 1:
+			high = i == 1
+		default:
+			high = i >= 2 && i <= accent
+		}
+		if high {
+			hl[i-1] = 'H'
+		} else {
+			hl[i-1] = 'L'
+		}
+	}
+	shape := string(hl)
+	if accent == 0 {
+		shape += "+particle-H"
+	}
+	return shape
+}
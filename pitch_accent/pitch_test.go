@@ -0,0 +1,66 @@
+// Copyright 2023 David Sansome
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMoras(t *testing.T) {
+	tests := []struct {
+		reading string
+		want    []string
+	}{
+		{"はし", []string{"は", "し"}},
+		{"がっこう", []string{"が", "っ", "こ", "う"}},
+		{"ぎゅうにゅう", []string{"ぎゅ", "う", "にゅ", "う"}},
+		{"きょう", []string{"きょ", "う"}},
+		{"ほん", []string{"ほ", "ん"}},
+	}
+	for _, tt := range tests {
+		if got := moras(tt.reading); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("moras(%q) = %v, want %v", tt.reading, got, tt.want)
+		}
+	}
+}
+
+func TestPitchPatternAndShape(t *testing.T) {
+	tests := []struct {
+		name        string
+		reading     string
+		accent      int
+		wantPattern string
+		wantShape   string
+	}{
+		{"箸 (chopsticks) is atamadaka", "はし", 1, patternAtamadaka, "HL"},
+		{"橋 (bridge) is odaka", "はし", 2, patternOdaka, "LH"},
+		{"端 (edge) is heiban", "はし", 0, patternHeiban, "LH+particle-H"},
+		{"雨 (rain) is atamadaka", "あめ", 1, patternAtamadaka, "HL"},
+		{"飴 (candy) is heiban", "あめ", 0, patternHeiban, "LH+particle-H"},
+		{"日本 is nakadaka", "にほん", 2, patternNakadaka, "LHL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := len(moras(tt.reading))
+			if got := pitchPattern(n, tt.accent); got != tt.wantPattern {
+				t.Errorf("pitchPattern(%d, %d) = %q, want %q", n, tt.accent, got, tt.wantPattern)
+			}
+			if got := pitchShape(n, tt.accent); got != tt.wantShape {
+				t.Errorf("pitchShape(%d, %d) = %q, want %q", n, tt.accent, got, tt.wantShape)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2023 David Sansome
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// defaultSourceWeight is the score given to a source's accents when no
+// weight is specified in its -source flag.
+const defaultSourceWeight = 1.0
+
+// source is one -source name=path[:weight] flag: an accents file to merge
+// into the index, plus the weight its accents should contribute to a
+// possibleAccents' score.
+type source struct {
+	name   string
+	path   string
+	weight float64
+}
+
+// sourceList collects repeated -source flags, implementing flag.Value so it
+// can be registered with flag.Var.
+type sourceList []source
+
+func (s *sourceList) String() string {
+	names := make([]string, len(*s))
+	for i, src := range *s {
+		names[i] = src.name
+	}
+	return strings.Join(names, ",")
+}
+
+func (s *sourceList) Set(value string) error {
+	name, rest, ok := strings.Cut(value, "=")
+	if !ok || name == "" || rest == "" {
+		return fmt.Errorf("-source must be in the form name=path[:weight], got %q", value)
+	}
+
+	path := rest
+	weight := defaultSourceWeight
+	if p, w, ok := strings.Cut(rest, ":"); ok {
+		path = p
+		parsedWeight, err := strconv.ParseFloat(w, 64)
+		if err != nil {
+			return fmt.Errorf("-source weight must be a number, got %q", value)
+		}
+		weight = parsedWeight
+	}
+
+	*s = append(*s, source{name: name, path: path, weight: weight})
+	return nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2023 David Sansome
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSourceListSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    source
+		wantErr bool
+	}{
+		{"name and path only", "nhk=nhk.txt", source{name: "nhk", path: "nhk.txt", weight: defaultSourceWeight}, false},
+		{"name, path, and weight", "nhk=nhk.txt:2.5", source{name: "nhk", path: "nhk.txt", weight: 2.5}, false},
+		{"missing =", "nhk.txt", source{}, true},
+		{"empty name", "=nhk.txt", source{}, true},
+		{"empty path", "nhk=", source{}, true},
+		{"non-numeric weight", "nhk=nhk.txt:abc", source{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s sourceList
+			err := s.Set(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Set(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := s[0]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Set(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}